@@ -1,16 +1,24 @@
 package deploy_test
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
 	"github.com/onsi/gomega/gbytes"
 
 	clihelper "github.com/rancher/fleet/integrationtests/cli"
 	"github.com/rancher/fleet/integrationtests/utils"
 	"github.com/rancher/fleet/internal/cmd/cli"
+	"github.com/rancher/fleet/internal/cmd/cli/deploy"
+	"github.com/rancher/fleet/pkg/apis/fleet.cattle.io/v1alpha1"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -118,6 +126,9 @@ var _ = Describe("Fleet CLI Deploy", func() {
 			Expect(buf).To(gbytes.Say("defaultNamespace: default"))
 			Expect(buf).To(gbytes.Say("objects"))
 			Expect(buf).To(gbytes.Say("- apiVersion: v1"))
+			Expect(buf).To(gbytes.Say("KIND +NAMESPACE +NAME +RESULT +REASON"))
+			Expect(buf).To(gbytes.Say(`"appliedDeploymentID": "s-ee0480cffe0c0da150814f6844d7c5bc49cc05c158c5ba1efe9a45142f36e:`))
+			Expect(buf).To(gbytes.Say(`"ready": true`))
 
 			cm := &corev1.ConfigMap{}
 			err = k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "test-simple-chart-config"}, cm)
@@ -146,6 +157,201 @@ var _ = Describe("Fleet CLI Deploy", func() {
 		})
 	})
 
+	When("Adding labels and annotations", func() {
+		BeforeEach(func() {
+			args = []string{
+				"--input-file", clihelper.AssetsPath + "bundledeployment/bd.yaml",
+				"--namespace", namespace,
+				"--label", "env=staging",
+				"--label", "owner=me",
+				"--annotation", "note=test",
+				"--annotation", "list=a,b",
+			}
+		})
+
+		It("adds them to the created resources", func() {
+			_, err := act(args)
+			Expect(err).NotTo(HaveOccurred())
+
+			cm := &corev1.ConfigMap{}
+			err = k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "test-simple-chart-config"}, cm)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cm.Labels).To(HaveKeyWithValue("env", "staging"))
+			Expect(cm.Labels).To(HaveKeyWithValue("owner", "me"))
+			Expect(cm.Annotations).To(HaveKeyWithValue("note", "test"))
+			Expect(cm.Annotations).To(HaveKeyWithValue("list", "a,b"))
+			Expect(cm.Annotations).NotTo(HaveKey("b"))
+		})
+
+		It("shows them in the --dry-run output", func() {
+			buf, err := act(append(args, "--dry-run"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(gbytes.Say("      note: test"))
+			Expect(buf).To(gbytes.Say("      env: staging"))
+			Expect(buf).To(gbytes.Say("      owner: me"))
+		})
+	})
+
+	When("Adding an invalid label", func() {
+		BeforeEach(func() {
+			args = []string{
+				"--input-file", clihelper.AssetsPath + "bundledeployment/bd.yaml",
+				"--label", "invalid key=value",
+			}
+		})
+
+		It("prints an error", func() {
+			_, err := act(args)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`label: Invalid value: "invalid key"`))
+		})
+	})
+
+	When("Adding a label without a value", func() {
+		BeforeEach(func() {
+			args = []string{
+				"--input-file", clihelper.AssetsPath + "bundledeployment/bd.yaml",
+				"--label", "foo",
+			}
+		})
+
+		It("prints an error", func() {
+			_, err := act(args)
+			Expect(err).To(MatchError(ContainSubstring(`invalid --label "foo", expected key=value`)))
+		})
+	})
+
+	When("Writing the status to a file", func() {
+		var statusFile string
+
+		BeforeEach(func() {
+			statusFile = filepath.Join(tmpdir, "status.json")
+			args = []string{
+				"--input-file", clihelper.AssetsPath + "bundledeployment/bd.yaml",
+				"--namespace", namespace,
+				"--status-output", statusFile,
+			}
+		})
+
+		It("writes a bundledeployment compatible status", func() {
+			buf, err := act(args)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(gbytes.Say("- apiVersion: v1"))
+			Expect(buf).To(gbytes.Say(`"appliedDeploymentID"`))
+
+			b, err := os.ReadFile(statusFile)
+			Expect(err).NotTo(HaveOccurred())
+			status := &deploy.Status{}
+			Expect(json.Unmarshal(b, status)).To(Succeed())
+
+			Expect(status.AppliedDeploymentID).To(Equal("s-ee0480cffe0c0da150814f6844d7c5bc49cc05c158c5ba1efe9a45142f36e:c32e813ecbf48f56833aa2267cd3d8758eecc94f9948fb0dea510147a57760b5"))
+			Expect(status.Release).To(HavePrefix(namespace + "/"))
+			Expect(status.Ready).To(BeTrue())
+			Expect(status.NonReadyStatus).To(BeEmpty())
+			Expect(status.Resources).To(ConsistOf(HaveField("Name", "test-simple-chart-config")))
+			Expect(status.Resources[0].Kind).To(Equal("ConfigMap"))
+			Expect(status.Resources[0].Namespace).To(Equal(namespace))
+			Expect(status.Results).To(ConsistOf(deploy.Result{
+				Kind:      "ConfigMap",
+				Namespace: namespace,
+				Name:      "test-simple-chart-config",
+				Result:    deploy.ResultCreated,
+			}))
+
+			var ready bool
+			for _, c := range status.Conditions {
+				if c.Type == string(v1alpha1.BundleDeploymentConditionReady) {
+					ready = c.Status == corev1.ConditionTrue
+				}
+			}
+			Expect(ready).To(BeTrue())
+		})
+	})
+
+	When("Writing the status to stdout", func() {
+		BeforeEach(func() {
+			args = []string{
+				"--kubeconfig", kubeconfigPath,
+				"--input-file", clihelper.AssetsPath + "bundledeployment/bd.yaml",
+				"--namespace", namespace,
+				"--status-output", "-",
+			}
+		})
+
+		It("prints only the status to stdout", func() {
+			cmd := cli.NewDeploy()
+			cmd.SetArgs(args)
+			out := gbytes.NewBuffer()
+			errOut := gbytes.NewBuffer()
+			cmd.SetOut(out)
+			cmd.SetErr(errOut)
+			Expect(cmd.Execute()).To(Succeed())
+
+			status := &deploy.Status{}
+			Expect(json.Unmarshal(out.Contents(), status)).To(Succeed())
+			Expect(status.Ready).To(BeTrue())
+			Expect(status.AppliedDeploymentID).To(HavePrefix("s-ee0480cffe0c0da150814f6844d7c5bc49cc05c158c5ba1efe9a45142f36e:"))
+
+			Expect(errOut).To(gbytes.Say("- apiVersion: v1"))
+			Expect(errOut).To(gbytes.Say("ConfigMap +" + namespace + " +test-simple-chart-config +created"))
+		})
+	})
+
+	When("Deploying with --no-retry", func() {
+		BeforeEach(func() {
+			args = []string{
+				"--input-file", clihelper.AssetsPath + "bundledeployment/bd.yaml",
+				"--namespace", namespace,
+				"--no-retry",
+			}
+		})
+
+		It("prints the status without per-object results", func() {
+			buf, err := act(args)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(gbytes.Say("- apiVersion: v1"))
+			Expect(buf).NotTo(gbytes.Say("KIND +NAMESPACE +NAME +RESULT +REASON"))
+			Expect(buf).To(gbytes.Say(`"appliedDeploymentID": "s-ee0480cffe0c0da150814f6844d7c5bc49cc05c158c5ba1efe9a45142f36e:`))
+			Expect(buf).To(gbytes.Say(`"ready": true`))
+			Expect(buf).NotTo(gbytes.Say(`"results"`))
+		})
+	})
+
+	When("Deploying a CRD and a custom resource of that kind", func() {
+		BeforeEach(func() {
+			args = []string{
+				"--input-file", clihelper.AssetsPath + "bundledeployment/bd-crd.yaml",
+				"--namespace", namespace,
+			}
+
+			// the CRD is cluster scoped and not removed with the namespace
+			DeferCleanup(func() {
+				crd := &unstructured.Unstructured{}
+				crd.SetAPIVersion("apiextensions.k8s.io/v1")
+				crd.SetKind("CustomResourceDefinition")
+				crd.SetName("foos.deploy.fleet.test")
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, crd))).To(Succeed())
+				Eventually(func() bool {
+					return apierrors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: crd.GetName()}, crd))
+				}).Should(BeTrue())
+			})
+		})
+
+		It("creates the CRD before the custom resource", func() {
+			buf, err := act(args)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(gbytes.Say("KIND +NAMESPACE +NAME +RESULT +REASON"))
+			Expect(buf).To(gbytes.Say("CustomResourceDefinition +foos.deploy.fleet.test +created"))
+			Expect(buf).To(gbytes.Say("Foo +" + namespace + " +test-foo +created"))
+
+			foo := &unstructured.Unstructured{}
+			foo.SetAPIVersion("deploy.fleet.test/v1")
+			foo.SetKind("Foo")
+			err = k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "test-foo"}, foo)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
 	When("Printing results with --dry-run", func() {
 		BeforeEach(func() {
 			args = []string{
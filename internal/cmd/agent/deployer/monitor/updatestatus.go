@@ -164,7 +164,7 @@ func (m *Monitor) updateFromResources(logger logr.Logger, bd *fleet.BundleDeploy
 		return err
 	}
 
-	bd.Status.NonReadyStatus = nonReady(logger, plan, bd.Spec.Options.IgnoreOptions)
+	bd.Status.NonReadyStatus = NonReady(logger, plan.Objects, bd.Spec.Options.IgnoreOptions)
 	bd.Status.ModifiedStatus = modified(plan, resourcesPreviousRelease)
 	bd.Status.Ready = false
 	bd.Status.NonModified = false
@@ -202,14 +202,16 @@ func (m *Monitor) updateFromResources(logger logr.Logger, bd *fleet.BundleDeploy
 	return nil
 }
 
-func nonReady(logger logr.Logger, plan apply.Plan, ignoreOptions fleet.IgnoreOptions) (result []fleet.NonReadyStatus) {
+// NonReady returns the status of up to ten objects, which are not ready
+// according to their summary. Conditions in ignoreOptions are not considered.
+func NonReady(logger logr.Logger, objs []runtime.Object, ignoreOptions fleet.IgnoreOptions) (result []fleet.NonReadyStatus) {
 	defer func() {
 		sort.Slice(result, func(i, j int) bool {
 			return result[i].UID < result[j].UID
 		})
 	}()
 
-	for _, obj := range plan.Objects {
+	for _, obj := range objs {
 		if len(result) >= 10 {
 			return result
 		}
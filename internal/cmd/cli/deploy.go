@@ -2,16 +2,23 @@ package cli
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
 
 	"github.com/spf13/cobra"
 	"helm.sh/helm/v3/pkg/cli"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	command "github.com/rancher/fleet/internal/cmd"
+	"github.com/rancher/fleet/internal/cmd/cli/deploy"
 	"github.com/rancher/fleet/internal/content"
 	"github.com/rancher/fleet/internal/helmdeployer"
 	"github.com/rancher/fleet/internal/manifest"
@@ -20,6 +27,7 @@ import (
 	wyaml "github.com/rancher/wrangler/v2/pkg/yaml"
 
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/yaml"
@@ -50,6 +58,14 @@ type Deploy struct {
 
 	// AgentNamespace is set as an annotation on the chart.yaml in the helm release. Fleet-agent will manage charts with a matching label.
 	AgentNamespace string `usage:"Set the agent namespace, normally cattle-fleet-system. If set, fleet agent will garbage collect the helm release, i.e. delete it if the bundledeployment is missing." short:"a"`
+
+	NoRetry bool `usage:"Deploy in a single attempt, without creating namespaces and CRDs first and without retrying on transient errors"`
+
+	StatusOutput string `usage:"Also write the deploy status, which is printed as JSON like a bundledeployment status, to this file. With -, the status is the only output on stdout and all other output is written to stderr"`
+
+	Label      []string `usage:"Add a label to every deployed resource, e.g. --label env=dev, can be repeated" split:"false"`
+	Annotation []string `usage:"Add an annotation to every deployed resource, e.g. --annotation owner=me, can be repeated" split:"false"`
+	Overwrite  bool     `usage:"Overwrite existing labels and annotations of deployed resources with the ones passed via --label and --annotation"`
 }
 
 func (d *Deploy) Run(cmd *cobra.Command, args []string) error {
@@ -60,6 +76,25 @@ func (d *Deploy) Run(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	}
 
+	labels, err := deploy.ParseKeyValues("label", d.Label)
+	if err != nil {
+		return err
+	}
+	annotations, err := deploy.ParseKeyValues("annotation", d.Annotation)
+	if err != nil {
+		return err
+	}
+	errs := metav1validation.ValidateLabels(labels, field.NewPath("label"))
+	errs = append(errs, apivalidation.ValidateAnnotations(annotations, field.NewPath("annotation"))...)
+	if len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+	metadata := helmdeployer.Metadata{
+		Labels:      labels,
+		Annotations: annotations,
+		Overwrite:   d.Overwrite,
+	}
+
 	b, err := os.ReadFile(d.InputFile)
 	if err != nil {
 		return err
@@ -116,7 +151,7 @@ func (d *Deploy) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	if d.DryRun {
-		resources, err := helmdeployer.Template(ctx, bd.Name, manifest, bd.Spec.Options)
+		resources, err := helmdeployer.Template(ctx, bd.Name, manifest, bd.Spec.Options, metadata)
 		if err != nil {
 			return err
 		}
@@ -146,28 +181,112 @@ func (d *Deploy) Run(cmd *cobra.Command, args []string) error {
 		defaultNamespace,
 		d.AgentNamespace,
 	)
+	deployer.SetMetadata(metadata)
 
 	if kubeconfig := flag.Lookup("kubeconfig").Value.String(); kubeconfig != "" {
 		// set KUBECONFIG env var so helm can find it
 		os.Setenv("KUBECONFIG", kubeconfig)
 	}
 
-	// Note: deployer does not check the bundles dependencies
-	err = deployer.Setup(ctx, client, cli.New().RESTClientGetter())
-	if err != nil {
+	// with -, stdout only contains the status, so it can be parsed
+	out := cmd.OutOrStdout()
+	if d.StatusOutput == "-" {
+		out = cmd.ErrOrStderr()
+	}
+
+	var (
+		resources *helmdeployer.Resources
+		results   []deploy.Result
+		deployErr error
+	)
+	if d.NoRetry {
+		// Note: deployer does not check the bundles dependencies
+		deployErr = deployer.Setup(ctx, client, cli.New().RESTClientGetter())
+		if deployErr == nil {
+			resources, deployErr = deployer.Deploy(ctx, bd.Name, manifest, bd.Spec.Options)
+		}
+		if deployErr == nil {
+			if err := d.print(out, resources); err != nil {
+				return err
+			}
+		}
+	} else {
+		// the rendered objects are only used to create namespaces and CRDs
+		// first, helm might render different objects, e.g. due to valuesFrom
+		rendered, err := helmdeployer.Template(ctx, bd.Name, manifest, bd.Spec.Options, metadata)
+		if err != nil {
+			return err
+		}
+		rendered = deploy.Order(rendered)
+
+		releaseNamespace, releaseName := deployer.ReleaseName(bd.Name, bd.Spec.Options)
+		existing := deploy.Existing(ctx, client, rendered, releaseNamespace)
+
+		created, err := deploy.CreatePrerequisites(ctx, client, rendered, deploy.Release{Name: releaseName, Namespace: releaseNamespace})
+		if err == nil {
+			err = deploy.Retry(ctx, deploy.DefaultRetry, func() error {
+				// a new getter discards the discovery information cached
+				// before the CRDs were established
+				// Note: deployer does not check the bundles dependencies
+				if err := deployer.Setup(ctx, client, cli.New().RESTClientGetter()); err != nil {
+					return err
+				}
+
+				var err error
+				resources, err = deployer.Deploy(ctx, bd.Name, manifest, bd.Spec.Options)
+				return err
+			})
+		}
+		deployErr = err
+
+		if deployErr != nil {
+			results = deploy.FailedResults(client, rendered, releaseNamespace, created, deployErr)
+		} else {
+			if err := d.print(out, resources); err != nil {
+				return err
+			}
+			results = deploy.Results(ctx, client, resources.Objects, resources.DefaultNamespace, existing)
+		}
+		if err := deploy.PrintResults(out, results); err != nil {
+			return err
+		}
+	}
+
+	if err := d.writeStatus(ctx, cmd, client, bd, resources, results, deployErr); err != nil {
 		return err
 	}
 
-	resources, err := deployer.Deploy(ctx, bd.Name, manifest, bd.Spec.Options)
+	return deployErr
+}
+
+// writeStatus prints the status of the deployment as JSON and writes it to
+// the status output file, if one is set.
+func (d *Deploy) writeStatus(ctx context.Context, cmd *cobra.Command, c client.Client, bd *v1alpha1.BundleDeployment, resources *helmdeployer.Resources, results []deploy.Result, deployErr error) error {
+	status, err := deploy.NewStatus(ctx, c, bd, resources, results, deployErr)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(status, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	b, err = yaml.Marshal(resources)
+	if _, err := fmt.Fprintln(cmd.OutOrStdout(), string(b)); err != nil {
+		return err
+	}
+	if d.StatusOutput == "" || d.StatusOutput == "-" {
+		return nil
+	}
+
+	return os.WriteFile(d.StatusOutput, b, 0600)
+}
+
+func (d *Deploy) print(w io.Writer, resources *helmdeployer.Resources) error {
+	b, err := yaml.Marshal(resources)
 	if err != nil {
 		return err
 	}
-	cmd.Println(string(b))
+	_, err = fmt.Fprintln(w, string(b))
 
-	return nil
+	return err
 }
@@ -0,0 +1,369 @@
+// Package deploy contains the helpers used by the fleet deploy command to
+// order objects, retry transient failures and report per-object results.
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	ResultCreated = "created"
+	ResultUpdated = "updated"
+	ResultFailed  = "failed"
+	ResultUnknown = "unknown"
+
+	// helm checks these before adopting an existing resource into a release
+	helmManagedByLabel        = "app.kubernetes.io/managed-by"
+	helmReleaseNameAnnotation = "meta.helm.sh/release-name"
+	helmReleaseNSAnnotation   = "meta.helm.sh/release-namespace"
+)
+
+var (
+	// DefaultRetry is used to retry the helm release on transient errors,
+	// e.g. while CRDs are not yet served or webhooks are not reachable.
+	DefaultRetry = wait.Backoff{
+		Steps:    6,
+		Duration: time.Second,
+		Factor:   2.0,
+		Jitter:   0.1,
+		Cap:      30 * time.Second,
+	}
+
+	// CRDEstablishedTimeout is the maximum time to wait for a CRD to be established.
+	CRDEstablishedTimeout = time.Minute
+)
+
+// Namespacer tells if an object is namespaced, it is implemented by client.Client.
+type Namespacer interface {
+	IsObjectNamespaced(obj runtime.Object) (bool, error)
+}
+
+// Release identifies the helm release, which will adopt the objects created
+// before the release is installed.
+type Release struct {
+	Name      string
+	Namespace string
+}
+
+// Result is the outcome of deploying a single object.
+type Result struct {
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Result    string `json:"result,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// IsPrerequisite returns true for objects other objects might depend on,
+// i.e. namespaces and custom resource definitions.
+func IsPrerequisite(obj runtime.Object) bool {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	switch {
+	case gvk.Group == "" && gvk.Kind == "Namespace":
+		return true
+	case gvk.Group == "apiextensions.k8s.io" && gvk.Kind == "CustomResourceDefinition":
+		return true
+	}
+	return false
+}
+
+// Order returns a copy of objs with namespaces and CRDs first, keeping the
+// relative order of all other objects.
+func Order(objs []runtime.Object) []runtime.Object {
+	result := make([]runtime.Object, len(objs))
+	copy(result, objs)
+	sort.SliceStable(result, func(i, j int) bool {
+		return IsPrerequisite(result[i]) && !IsPrerequisite(result[j])
+	})
+	return result
+}
+
+// IsTransient returns true for errors which are expected to go away once
+// recently created CRDs are served or webhooks become reachable.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if meta.IsNoMatchError(err) {
+		return true
+	}
+	// helm wraps errors without keeping the error chain
+	msg := err.Error()
+	for _, s := range []string{
+		"no matches for kind",
+		"failed calling webhook",
+		"conversion webhook",
+		"connection refused",
+		"no endpoints available for service",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry calls fn until it succeeds, returns an error which is not transient
+// or the backoff is exhausted. The last error is returned.
+func Retry(ctx context.Context, backoff wait.Backoff, fn func() error) error {
+	logger := log.FromContext(ctx).WithName("deploy")
+	return retry.OnError(backoff, IsTransient, func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn()
+		if IsTransient(err) {
+			logger.Info("Transient error, retrying", "error", err.Error())
+		}
+		return err
+	})
+}
+
+// CreatePrerequisites creates missing namespaces and CRDs from objs, adding
+// the metadata helm needs to adopt them into release. It waits for created
+// CRDs to be established. Existing objects are left for helm to update.
+// The keys of the created objects are returned.
+func CreatePrerequisites(ctx context.Context, c client.Client, objs []runtime.Object, release Release) (map[string]bool, error) {
+	created := map[string]bool{}
+	var crds []*unstructured.Unstructured
+	for _, obj := range objs {
+		if !IsPrerequisite(obj) {
+			continue
+		}
+
+		u, err := toUnstructured(obj)
+		if err != nil {
+			return created, err
+		}
+		labels := u.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[helmManagedByLabel] = "Helm"
+		u.SetLabels(labels)
+		annotations := u.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[helmReleaseNameAnnotation] = release.Name
+		annotations[helmReleaseNSAnnotation] = release.Namespace
+		u.SetAnnotations(annotations)
+
+		if err := c.Create(ctx, u); apierrors.IsAlreadyExists(err) {
+			continue
+		} else if err != nil {
+			return created, fmt.Errorf("failed to create %s %s: %w", u.GetKind(), u.GetName(), err)
+		}
+		created[Key(c, obj, release.Namespace)] = true
+		if u.GetKind() == "CustomResourceDefinition" {
+			crds = append(crds, u)
+		}
+	}
+
+	for _, crd := range crds {
+		if err := waitForEstablished(ctx, c, crd); err != nil {
+			return created, err
+		}
+	}
+
+	return created, nil
+}
+
+func waitForEstablished(ctx context.Context, c client.Client, crd *unstructured.Unstructured) error {
+	err := wait.PollUntilContextTimeout(ctx, 500*time.Millisecond, CRDEstablishedTimeout, true, func(ctx context.Context) (bool, error) {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(crd.GroupVersionKind())
+		if err := c.Get(ctx, types.NamespacedName{Name: crd.GetName()}, u); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+		for _, cond := range conditions {
+			m, ok := cond.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if m["type"] == "Established" && m["status"] == "True" {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("CRD %s was not established: %w", crd.GetName(), err)
+	}
+	return nil
+}
+
+// Existing looks up which of objs exist, before the release is installed.
+// Objects which could not be looked up are missing from the returned map.
+func Existing(ctx context.Context, c client.Client, objs []runtime.Object, defaultNamespace string) map[string]bool {
+	existing := map[string]bool{}
+	for _, obj := range objs {
+		u, err := toUnstructured(obj)
+		if err != nil {
+			continue
+		}
+		key := Key(c, obj, defaultNamespace)
+		err = c.Get(ctx, types.NamespacedName{Namespace: namespace(c, u, defaultNamespace), Name: u.GetName()}, u)
+		switch {
+		case err == nil:
+			existing[key] = true
+		case apierrors.IsNotFound(err), meta.IsNoMatchError(err):
+			existing[key] = false
+		}
+	}
+	return existing
+}
+
+// Results returns the result for each object of a successful release. Objects
+// which existed before the release, as returned by Existing, are reported as
+// updated, all others as created.
+func Results(ctx context.Context, c client.Client, objs []runtime.Object, defaultNamespace string, existing map[string]bool) []Result {
+	results := make([]Result, 0, len(objs))
+	for _, obj := range objs {
+		u, err := toUnstructured(obj)
+		if err != nil {
+			continue
+		}
+		r := newResult(c, u, defaultNamespace)
+		existed, ok := existing[Key(c, obj, defaultNamespace)]
+
+		err = c.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: r.Name}, u)
+		switch {
+		case err != nil:
+			r.Result = ResultUnknown
+			r.Reason = err.Error()
+		case !ok:
+			r.Result = ResultUnknown
+			r.Reason = "could not be looked up before the release"
+		case existed:
+			r.Result = ResultUpdated
+		default:
+			r.Result = ResultCreated
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// FailedResults returns the result for each object of a failed release. Only
+// the objects in created, which were created before the release, are known
+// to exist. Objects named in deployErr are reported as failed with it as the
+// reason, it is unknown whether helm applied any of the other objects.
+func FailedResults(c Namespacer, objs []runtime.Object, defaultNamespace string, created map[string]bool, deployErr error) []Result {
+	results := make([]Result, 0, len(objs))
+	for _, obj := range objs {
+		u, err := toUnstructured(obj)
+		if err != nil {
+			continue
+		}
+		r := newResult(c, u, defaultNamespace)
+		switch {
+		case created[Key(c, obj, defaultNamespace)]:
+			r.Result = ResultCreated
+		case isNamed(deployErr, u):
+			r.Result = ResultFailed
+			r.Reason = deployErr.Error()
+		default:
+			r.Result = ResultUnknown
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// isNamed returns true if err names u, e.g. `configmaps "cm" is invalid` or
+// `resource mapping not found for name: "foo" ... no matches for kind "Foo"`.
+func isNamed(err error, u *unstructured.Unstructured) bool {
+	msg := err.Error()
+	if !strings.Contains(msg, strconv.Quote(u.GetName())) {
+		return false
+	}
+	return strings.Contains(strings.ToLower(msg), strings.ToLower(u.GetKind()))
+}
+
+func newResult(c Namespacer, u *unstructured.Unstructured, defaultNamespace string) Result {
+	return Result{
+		Kind:      u.GetKind(),
+		Namespace: namespace(c, u, defaultNamespace),
+		Name:      u.GetName(),
+	}
+}
+
+// PrintResults prints results as a table.
+func PrintResults(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tNAMESPACE\tNAME\tRESULT\tREASON")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Kind, r.Namespace, r.Name, r.Result, r.Reason)
+	}
+	return tw.Flush()
+}
+
+// ParseKeyValues parses entries in the form key=value, as passed to the named
+// flag. Values may contain any character, including '=' and ','.
+func ParseKeyValues(flag string, entries []string) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	result := map[string]string{}
+	for _, entry := range entries {
+		k, v, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --%s %q, expected key=value", flag, entry)
+		}
+		result[k] = v
+	}
+	return result, nil
+}
+
+// Key returns a unique key for obj. Namespaced objects without a namespace
+// are keyed with defaultNamespace.
+func Key(c Namespacer, obj runtime.Object, defaultNamespace string) string {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	u, err := toUnstructured(obj)
+	if err != nil {
+		return gvk.String()
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", gvk.Group, gvk.Kind, namespace(c, u, defaultNamespace), u.GetName())
+}
+
+// namespace returns the namespace of u, defaultNamespace if it is namespaced
+// but has none set. Objects of unknown kinds are considered namespaced.
+func namespace(c Namespacer, u *unstructured.Unstructured, defaultNamespace string) string {
+	if ns := u.GetNamespace(); ns != "" {
+		return ns
+	}
+	if namespaced, err := c.IsObjectNamespaced(u); err == nil && !namespaced {
+		return ""
+	}
+	return defaultNamespace
+}
+
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.DeepCopy(), nil
+	}
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: data}, nil
+}
@@ -0,0 +1,307 @@
+package deploy_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/rancher/fleet/internal/cmd/cli/deploy"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func object(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func TestOrder(t *testing.T) {
+	objs := []runtime.Object{
+		object("v1", "ConfigMap", "", "cm"),
+		object("example.com/v1", "Foo", "", "foo"),
+		object("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "foos.example.com"),
+		object("v1", "Secret", "", "secret"),
+		object("v1", "Namespace", "", "ns"),
+	}
+
+	ordered := deploy.Order(objs)
+
+	var kinds []string
+	for _, obj := range ordered {
+		kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	expected := "CustomResourceDefinition,Namespace,ConfigMap,Foo,Secret"
+	if got := strings.Join(kinds, ","); got != expected {
+		t.Errorf("expected order %s, got %s", expected, got)
+	}
+	if objs[0].GetObjectKind().GroupVersionKind().Kind != "ConfigMap" {
+		t.Errorf("expected input to be unchanged")
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"nil": {
+			err:      nil,
+			expected: false,
+		},
+		"no match error": {
+			err:      &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "example.com", Kind: "Foo"}},
+			expected: true,
+		},
+		"wrapped helm no match error": {
+			err:      errors.New(`unable to build kubernetes objects from release manifest: resource mapping not found for name: "foo": no matches for kind "Foo" in version "example.com/v1"`),
+			expected: true,
+		},
+		"webhook error": {
+			err:      errors.New(`Internal error occurred: failed calling webhook "validate.example.com": dial tcp 10.0.0.1:443: connect: connection refused`),
+			expected: true,
+		},
+		"invalid object": {
+			err:      errors.New(`ConfigMap "cm" is invalid: metadata.name: Invalid value`),
+			expected: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := deploy.IsTransient(test.err); got != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func newClient(objs ...runtime.Object) client.Client {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{
+		{Version: "v1"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1"},
+		{Group: "apiextensions.k8s.io", Version: "v1"},
+	})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}, meta.RESTScopeRoot)
+
+	return fake.NewClientBuilder().WithRESTMapper(mapper).WithRuntimeObjects(objs...).Build()
+}
+
+func TestKey(t *testing.T) {
+	c := newClient()
+
+	tests := map[string]struct {
+		obj      runtime.Object
+		expected string
+	}{
+		"namespaced without namespace": {
+			obj:      object("v1", "ConfigMap", "", "cm"),
+			expected: "/ConfigMap/default/cm",
+		},
+		"namespaced with namespace": {
+			obj:      object("v1", "ConfigMap", "other", "cm"),
+			expected: "/ConfigMap/other/cm",
+		},
+		"cluster scoped": {
+			obj:      object("rbac.authorization.k8s.io/v1", "ClusterRole", "", "role"),
+			expected: "rbac.authorization.k8s.io/ClusterRole//role",
+		},
+		"unknown kind": {
+			obj:      object("example.com/v1", "Foo", "", "foo"),
+			expected: "example.com/Foo/default/foo",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := deploy.Key(c, test.obj, "default"); got != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestResults(t *testing.T) {
+	// creation timestamps are set by the API server, whose clock might
+	// differ from the local one, so they must not affect the results
+	now := time.Now()
+	created := func(u *unstructured.Unstructured, ts time.Time) *unstructured.Unstructured {
+		u = u.DeepCopy()
+		u.SetCreationTimestamp(metav1.NewTime(ts))
+		return u
+	}
+
+	oldCM := object("v1", "ConfigMap", "default", "old")
+	newCM := object("v1", "ConfigMap", "default", "new")
+	role := object("rbac.authorization.k8s.io/v1", "ClusterRole", "", "role")
+	c := newClient(
+		created(oldCM, now.Add(time.Hour)),
+		created(newCM, now.Add(-time.Hour)),
+		created(role, now.Add(time.Hour)),
+		created(object("v1", "ConfigMap", "default", "unrendered"), now),
+	)
+
+	existing := map[string]bool{
+		deploy.Key(c, oldCM, "default"): true,
+		deploy.Key(c, newCM, "default"): false,
+		deploy.Key(c, role, "default"):  true,
+		"/ConfigMap/default/missing":    false,
+	}
+
+	objs := []runtime.Object{
+		object("v1", "ConfigMap", "", "old"),
+		object("v1", "ConfigMap", "", "new"),
+		role,
+		object("v1", "ConfigMap", "", "unrendered"),
+		object("v1", "ConfigMap", "", "missing"),
+	}
+
+	results := deploy.Results(context.TODO(), c, objs, "default", existing)
+	expected := []deploy.Result{
+		{Kind: "ConfigMap", Namespace: "default", Name: "old", Result: deploy.ResultUpdated},
+		{Kind: "ConfigMap", Namespace: "default", Name: "new", Result: deploy.ResultCreated},
+		{Kind: "ClusterRole", Name: "role", Result: deploy.ResultUpdated},
+		{Kind: "ConfigMap", Namespace: "default", Name: "unrendered", Result: deploy.ResultUnknown, Reason: "could not be looked up before the release"},
+		{Kind: "ConfigMap", Namespace: "default", Name: "missing", Result: deploy.ResultUnknown, Reason: `configmaps "missing" not found`},
+	}
+	if diff := cmp.Diff(expected, results); diff != "" {
+		t.Errorf("unexpected results (-want +got):\n%s", diff)
+	}
+}
+
+func TestExisting(t *testing.T) {
+	cm := object("v1", "ConfigMap", "default", "cm")
+	role := object("rbac.authorization.k8s.io/v1", "ClusterRole", "", "role")
+	c := newClient(cm, role)
+
+	objs := []runtime.Object{
+		object("v1", "ConfigMap", "", "cm"),
+		object("v1", "ConfigMap", "", "new"),
+		role,
+		object("example.com/v1", "Foo", "", "foo"),
+	}
+
+	existing := deploy.Existing(context.TODO(), c, objs, "default")
+	expected := map[string]bool{
+		"/ConfigMap/default/cm":                       true,
+		"/ConfigMap/default/new":                      false,
+		"rbac.authorization.k8s.io/ClusterRole//role": true,
+		"example.com/Foo/default/foo":                 false,
+	}
+	if diff := cmp.Diff(expected, existing); diff != "" {
+		t.Errorf("unexpected existing objects (-want +got):\n%s", diff)
+	}
+}
+
+func TestFailedResults(t *testing.T) {
+	c := newClient()
+	crd := object("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "foos.example.com")
+	objs := []runtime.Object{
+		crd,
+		object("v1", "ConfigMap", "", "cm"),
+		object("v1", "ConfigMap", "", "other"),
+		object("rbac.authorization.k8s.io/v1", "ClusterRole", "", "cm"),
+	}
+	created := map[string]bool{deploy.Key(c, crd, "default"): true}
+	deployErr := errors.New(`failed to create resource: configmaps "cm" is invalid: data: Invalid value`)
+
+	results := deploy.FailedResults(c, objs, "default", created, deployErr)
+	expected := []deploy.Result{
+		{Kind: "CustomResourceDefinition", Name: "foos.example.com", Result: deploy.ResultCreated},
+		{Kind: "ConfigMap", Namespace: "default", Name: "cm", Result: deploy.ResultFailed, Reason: deployErr.Error()},
+		{Kind: "ConfigMap", Namespace: "default", Name: "other", Result: deploy.ResultUnknown},
+		{Kind: "ClusterRole", Name: "cm", Result: deploy.ResultUnknown},
+	}
+	if diff := cmp.Diff(expected, results); diff != "" {
+		t.Errorf("unexpected results (-want +got):\n%s", diff)
+	}
+
+	var buf bytes.Buffer
+	if err := deploy.PrintResults(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "is invalid"); n != 1 {
+		t.Errorf("expected the reason in a single row, got %d:\n%s", n, buf.String())
+	}
+}
+
+func TestFailedResultsNoMatch(t *testing.T) {
+	c := newClient()
+	objs := []runtime.Object{
+		object("v1", "ConfigMap", "", "foo"),
+		object("example.com/v1", "Foo", "", "foo"),
+	}
+	deployErr := errors.New(`unable to build kubernetes objects from release manifest: resource mapping not found for name: "foo" namespace: "" from "": no matches for kind "Foo" in version "example.com/v1"`)
+
+	results := deploy.FailedResults(c, objs, "default", nil, deployErr)
+	expected := []deploy.Result{
+		{Kind: "ConfigMap", Namespace: "default", Name: "foo", Result: deploy.ResultUnknown},
+		{Kind: "Foo", Namespace: "default", Name: "foo", Result: deploy.ResultFailed, Reason: deployErr.Error()},
+	}
+	if diff := cmp.Diff(expected, results); diff != "" {
+		t.Errorf("unexpected results (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	tests := map[string]struct {
+		entries   []string
+		expected  map[string]string
+		expectErr bool
+	}{
+		"no entries": {
+			entries:  nil,
+			expected: nil,
+		},
+		"key value pairs": {
+			entries:  []string{"env=dev", "owner=me"},
+			expected: map[string]string{"env": "dev", "owner": "me"},
+		},
+		"value with commas and equal signs": {
+			entries:  []string{"note=a,b", "query=x=1,y=2"},
+			expected: map[string]string{"note": "a,b", "query": "x=1,y=2"},
+		},
+		"empty value": {
+			entries:  []string{"env="},
+			expected: map[string]string{"env": ""},
+		},
+		"missing equal sign": {
+			entries:   []string{"env=dev", "foo"},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := deploy.ParseKeyValues("label", test.entries)
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.expected, result); diff != "" {
+				t.Errorf("unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
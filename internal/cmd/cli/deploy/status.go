@@ -0,0 +1,106 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rancher/fleet/internal/cmd/agent/deployer/monitor"
+	"github.com/rancher/fleet/internal/helmdeployer"
+	fleet "github.com/rancher/fleet/pkg/apis/fleet.cattle.io/v1alpha1"
+
+	"github.com/rancher/wrangler/v2/pkg/condition"
+	"github.com/rancher/wrangler/v2/pkg/summary"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Status is the result of a deploy, shaped like the status the agent writes
+// to a BundleDeployment, with the apply result of each object added.
+type Status struct {
+	fleet.BundleDeploymentStatus `json:",inline"`
+
+	Results []Result `json:"results,omitempty"`
+}
+
+// NewStatus returns the status for deploying bd. The readiness of the
+// deployed objects is determined like the agent does, by summarizing the
+// objects read from the cluster. Objects which can't be read are reported
+// as not ready.
+func NewStatus(ctx context.Context, c client.Client, bd *fleet.BundleDeployment, resources *helmdeployer.Resources, results []Result, deployErr error) (*Status, error) {
+	status := &Status{Results: results}
+	status.AppliedDeploymentID = bd.Spec.DeploymentID
+	status.Resources = []fleet.BundleDeploymentResource{}
+
+	if deployErr != nil {
+		condition.Cond(fleet.BundleDeploymentConditionInstalled).SetError(&status.BundleDeploymentStatus, "", deployErr)
+		condition.Cond(fleet.BundleDeploymentConditionReady).SetError(&status.BundleDeploymentStatus, "", deployErr)
+		return status, nil
+	}
+	condition.Cond(fleet.BundleDeploymentConditionInstalled).SetStatusBool(&status.BundleDeploymentStatus, true)
+
+	status.Release = resources.ID
+	// objects have just been applied from the release
+	status.NonModified = true
+
+	// objects which can't be read are not ready, as their state is unknown
+	var live []runtime.Object
+	var unknown []fleet.NonReadyStatus
+	for _, obj := range resources.Objects {
+		u, err := toUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		ns := namespace(c, u, resources.DefaultNamespace)
+		version, kind := u.GroupVersionKind().ToAPIVersionAndKind()
+		resource := fleet.BundleDeploymentResource{
+			Kind:       kind,
+			APIVersion: version,
+			Namespace:  ns,
+			Name:       u.GetName(),
+		}
+
+		if err := c.Get(ctx, types.NamespacedName{Namespace: ns, Name: u.GetName()}, u); err != nil {
+			unknown = append(unknown, fleet.NonReadyStatus{
+				Kind:       kind,
+				APIVersion: version,
+				Namespace:  ns,
+				Name:       u.GetName(),
+				Summary: summary.Summary{
+					State:   ResultUnknown,
+					Error:   true,
+					Message: []string{err.Error()},
+				},
+			})
+		} else {
+			live = append(live, u)
+			resource.CreatedAt = u.GetCreationTimestamp()
+		}
+		status.Resources = append(status.Resources, resource)
+	}
+
+	logger := log.FromContext(ctx).WithName("deploy")
+	status.NonReadyStatus = monitor.NonReady(logger, live, bd.Spec.Options.IgnoreOptions)
+	for _, s := range unknown {
+		if len(status.NonReadyStatus) >= 10 {
+			break
+		}
+		status.NonReadyStatus = append(status.NonReadyStatus, s)
+	}
+	status.Ready = len(status.NonReadyStatus) == 0
+
+	var readyErr error
+	if !status.Ready {
+		readyErr = errors.New(status.NonReadyStatus[0].String())
+	}
+	condition.Cond(fleet.BundleDeploymentConditionReady).SetError(&status.BundleDeploymentStatus, "", readyErr)
+
+	for i := range status.NonReadyStatus {
+		status.NonReadyStatus[i].Summary.Relationships = nil
+		status.NonReadyStatus[i].Summary.Attributes = nil
+	}
+
+	return status, nil
+}
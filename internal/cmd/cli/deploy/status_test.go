@@ -0,0 +1,63 @@
+package deploy_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rancher/fleet/internal/cmd/cli/deploy"
+	"github.com/rancher/fleet/internal/helmdeployer"
+	fleet "github.com/rancher/fleet/pkg/apis/fleet.cattle.io/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestNewStatus(t *testing.T) {
+	cm := object("v1", "ConfigMap", "default", "cm")
+	role := object("rbac.authorization.k8s.io/v1", "ClusterRole", "", "role")
+	c := newClient(cm, role)
+	bd := &fleet.BundleDeployment{Spec: fleet.BundleDeploymentSpec{DeploymentID: "s-1:2"}}
+
+	t.Run("ready", func(t *testing.T) {
+		resources := &helmdeployer.Resources{
+			ID:               "default/release:1",
+			DefaultNamespace: "default",
+			Objects:          []runtime.Object{object("v1", "ConfigMap", "", "cm"), role},
+		}
+
+		status, err := deploy.NewStatus(context.TODO(), c, bd, resources, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if status.AppliedDeploymentID != "s-1:2" || status.Release != "default/release:1" {
+			t.Errorf("unexpected status %+v", status.BundleDeploymentStatus)
+		}
+		if !status.Ready || len(status.NonReadyStatus) != 0 {
+			t.Errorf("expected status to be ready, got %+v", status.NonReadyStatus)
+		}
+		if len(status.Resources) != 2 || status.Resources[0].Namespace != "default" || status.Resources[1].Namespace != "" {
+			t.Errorf("unexpected resources %+v", status.Resources)
+		}
+	})
+
+	t.Run("object can't be read", func(t *testing.T) {
+		resources := &helmdeployer.Resources{
+			ID:               "default/release:1",
+			DefaultNamespace: "default",
+			Objects:          []runtime.Object{cm, object("v1", "ConfigMap", "default", "missing")},
+		}
+
+		status, err := deploy.NewStatus(context.TODO(), c, bd, resources, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if status.Ready {
+			t.Errorf("expected status not to be ready")
+		}
+		if len(status.NonReadyStatus) != 1 || status.NonReadyStatus[0].Name != "missing" || status.NonReadyStatus[0].Summary.State != deploy.ResultUnknown {
+			t.Errorf("unexpected non ready status %+v", status.NonReadyStatus)
+		}
+		if len(status.Resources) != 2 {
+			t.Errorf("expected both objects in resources, got %+v", status.Resources)
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package target
+
+import (
+	"context"
+	"testing"
+
+	fleet "github.com/rancher/fleet/pkg/apis/fleet.cattle.io/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewBundleMapping(t *testing.T) {
+	bundle := func(labels map[string]string) *fleet.Bundle {
+		return &fleet.Bundle{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "workspace",
+				Name:      "bundle",
+				Labels:    labels,
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		bundleSelector *metav1.LabelSelector
+		nsSelector     *metav1.LabelSelector
+		expectErr      bool
+		matches        map[string]bool
+	}{
+		"nil bundle selector never matches": {
+			bundleSelector: nil,
+			nsSelector:     &metav1.LabelSelector{},
+			matches: map[string]bool{
+				"": false,
+			},
+		},
+		"nil namespace selector never matches": {
+			bundleSelector: &metav1.LabelSelector{},
+			nsSelector:     nil,
+			matches: map[string]bool{
+				"": false,
+			},
+		},
+		"empty selector matches everything": {
+			bundleSelector: &metav1.LabelSelector{},
+			nsSelector:     &metav1.LabelSelector{},
+			matches: map[string]bool{
+				"":         true,
+				"env=prod": true,
+			},
+		},
+		"matchLabels": {
+			bundleSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			nsSelector:     &metav1.LabelSelector{},
+			matches: map[string]bool{
+				"":         false,
+				"env=prod": true,
+				"env=dev":  false,
+			},
+		},
+		"matchExpressions In": {
+			bundleSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"prod", "staging"}},
+			}},
+			nsSelector: &metav1.LabelSelector{},
+			matches: map[string]bool{
+				"":            false,
+				"env=prod":    true,
+				"env=staging": true,
+				"env=dev":     false,
+			},
+		},
+		"matchExpressions NotIn and Exists": {
+			bundleSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"dev"}},
+				{Key: "team", Operator: metav1.LabelSelectorOpExists},
+			}},
+			nsSelector: &metav1.LabelSelector{},
+			matches: map[string]bool{
+				"team=a":          true,
+				"env=prod,team=a": true,
+				"env=dev,team=a":  false,
+				"env=prod":        false,
+			},
+		},
+		"matchExpressions DoesNotExist combined with matchLabels": {
+			bundleSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"env": "prod"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "skip", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
+			nsSelector: &metav1.LabelSelector{},
+			matches: map[string]bool{
+				"env=prod":             true,
+				"env=prod,skip=true":   false,
+				"env=staging,team=foo": false,
+			},
+		},
+		"invalid bundle selector operator": {
+			bundleSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: "Unknown", Values: []string{"prod"}},
+			}},
+			nsSelector: &metav1.LabelSelector{},
+			expectErr:  true,
+		},
+		"invalid bundle selector values for Exists": {
+			bundleSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpExists, Values: []string{"prod"}},
+			}},
+			nsSelector: &metav1.LabelSelector{},
+			expectErr:  true,
+		},
+		"invalid namespace selector": {
+			bundleSelector: &metav1.LabelSelector{},
+			nsSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpIn},
+			}},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mapping := &fleet.BundleNamespaceMapping{
+				ObjectMeta:        metav1.ObjectMeta{Namespace: "workspace", Name: "mapping"},
+				BundleSelector:    test.bundleSelector,
+				NamespaceSelector: test.nsSelector,
+			}
+
+			matcher, err := newBundleMapping(mapping)
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error for invalid selector")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for labels, expected := range test.matches {
+				set, err := parseLabels(labels)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := matcher.Matches(bundle(set)); got != expected {
+					t.Errorf("labels %q: expected match %v, got %v", labels, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBundleMappingOtherNamespace(t *testing.T) {
+	matcher, err := newBundleMapping(&fleet.BundleNamespaceMapping{
+		ObjectMeta:        metav1.ObjectMeta{Namespace: "workspace", Name: "mapping"},
+		BundleSelector:    &metav1.LabelSelector{},
+		NamespaceSelector: &metav1.LabelSelector{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := &fleet.Bundle{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "bundle"}}
+	if matcher.Matches(bundle) {
+		t.Errorf("expected mapping not to match bundles in other namespaces")
+	}
+}
+
+func TestBundleMappingMatchesNamespace(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"env": "prod", "team": "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "staging", Labels: map[string]string{"env": "staging"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev", Labels: map[string]string{"env": "dev", "team": "b"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+	).Build()
+
+	tests := map[string]struct {
+		nsSelector *metav1.LabelSelector
+		expected   map[string]bool
+	}{
+		"empty selector": {
+			nsSelector: &metav1.LabelSelector{},
+			expected: map[string]bool{
+				"prod": true, "staging": true, "dev": true, "unlabeled": true, "missing": false,
+			},
+		},
+		"matchLabels": {
+			nsSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			expected: map[string]bool{
+				"prod": true, "staging": false, "dev": false, "unlabeled": false,
+			},
+		},
+		"matchExpressions In": {
+			nsSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"prod", "staging"}},
+			}},
+			expected: map[string]bool{
+				"prod": true, "staging": true, "dev": false, "unlabeled": false, "missing": false,
+			},
+		},
+		"matchExpressions NotIn": {
+			nsSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"dev"}},
+			}},
+			expected: map[string]bool{
+				"prod": true, "staging": true, "dev": false, "unlabeled": true,
+			},
+		},
+		"matchExpressions Exists": {
+			nsSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "team", Operator: metav1.LabelSelectorOpExists},
+			}},
+			expected: map[string]bool{
+				"prod": true, "staging": false, "dev": true, "unlabeled": false,
+			},
+		},
+		"matchExpressions DoesNotExist combined with In": {
+			nsSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"prod", "staging", "dev"}},
+				{Key: "team", Operator: metav1.LabelSelectorOpDoesNotExist},
+			}},
+			expected: map[string]bool{
+				"prod": false, "staging": true, "dev": false, "unlabeled": false,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			matcher, err := newBundleMapping(&fleet.BundleNamespaceMapping{
+				ObjectMeta:        metav1.ObjectMeta{Namespace: "workspace", Name: "mapping"},
+				BundleSelector:    &metav1.LabelSelector{},
+				NamespaceSelector: test.nsSelector,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for ns, expected := range test.expected {
+				if got := matcher.MatchesNamespace(context.TODO(), c, ns); got != expected {
+					t.Errorf("namespace %q: expected match %v, got %v", ns, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func parseLabels(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	selector, err := metav1.ParseToLabelSelector(s)
+	if err != nil {
+		return nil, err
+	}
+	return selector.MatchLabels, nil
+}
@@ -49,6 +49,15 @@ type Helm struct {
 	defaultNamespace string
 	labelPrefix      string
 	labelSuffix      string
+	metadata         Metadata
+}
+
+// Metadata contains labels and annotations, which are added to every
+// deployed object. Existing keys are only replaced if Overwrite is set.
+type Metadata struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	Overwrite   bool
 }
 
 type Resources struct {
@@ -78,6 +87,11 @@ func New(agentNamespace, defaultNamespace, labelPrefix, labelSuffix string) *Hel
 	}
 }
 
+// SetMetadata sets the labels and annotations added to every deployed object.
+func (h *Helm) SetMetadata(metadata Metadata) {
+	h.metadata = metadata
+}
+
 func (h *Helm) Setup(ctx context.Context, client client.Client, getter genericclioptions.RESTClientGetter) error {
 	h.client = client
 	h.getter = getter
@@ -121,6 +135,12 @@ func (h *Helm) getOpts(bundleID string, options fleet.BundleDeploymentOptions) (
 	return timeout, ns, name2.HelmReleaseName(bundleID)
 }
 
+// ReleaseName returns the namespace and name of the helm release used for the bundledeployment.
+func (h *Helm) ReleaseName(bundleID string, options fleet.BundleDeploymentOptions) (string, string) {
+	_, ns, name := h.getOpts(bundleID, options)
+	return ns, name
+}
+
 func (h *Helm) getCfg(ctx context.Context, namespace, serviceAccountName string) (action.Configuration, error) {
 	var (
 		cfg    action.Configuration
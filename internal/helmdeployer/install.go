@@ -115,6 +115,7 @@ func (h *Helm) install(ctx context.Context, bundleID string, manifest *manifest.
 		manifest:    manifest,
 		opts:        options,
 		chart:       chart,
+		metadata:    h.metadata,
 	}
 
 	if !h.useGlobalCfg {
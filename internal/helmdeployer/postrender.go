@@ -30,6 +30,7 @@ type postRender struct {
 	chart       *chart.Chart
 	mapper      meta.RESTMapper
 	opts        fleet.BundleDeploymentOptions
+	metadata    Metadata
 }
 
 func (p *postRender) Run(renderedManifests *bytes.Buffer) (modifiedManifests *bytes.Buffer, err error) {
@@ -87,6 +88,8 @@ func (p *postRender) Run(renderedManifests *bytes.Buffer) (modifiedManifests *by
 			obj.GetObjectKind().GroupVersionKind().Kind == CRDKind {
 			annotations[kube.ResourcePolicyAnno] = kube.KeepPolicy
 		}
+		m.SetLabels(addMetadata(m.GetLabels(), p.metadata.Labels, p.metadata.Overwrite))
+		m.SetAnnotations(addMetadata(m.GetAnnotations(), p.metadata.Annotations, p.metadata.Overwrite))
 		m.SetLabels(mergeMaps(m.GetLabels(), labels))
 		m.SetAnnotations(mergeMaps(m.GetAnnotations(), annotations))
 
@@ -110,3 +113,22 @@ func (p *postRender) Run(renderedManifests *bytes.Buffer) (modifiedManifests *by
 	data, err = yaml.ToBytes(objs)
 	return bytes.NewBuffer(data), err
 }
+
+// addMetadata adds the extra labels or annotations to current. Keys which
+// exist in current are only replaced if overwrite is set.
+func addMetadata(current, extra map[string]string, overwrite bool) map[string]string {
+	if len(extra) == 0 {
+		return current
+	}
+	result := map[string]string{}
+	for k, v := range current {
+		result[k] = v
+	}
+	for k, v := range extra {
+		if _, ok := result[k]; ok && !overwrite {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
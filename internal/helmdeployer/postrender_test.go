@@ -117,3 +117,103 @@ func TestPostRenderer_Run_DeleteCRDs(t *testing.T) {
 		})
 	}
 }
+
+func TestPostRenderer_Run_Metadata(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cm",
+			Labels:      map[string]string{"env": "dev"},
+			Annotations: map[string]string{"note": "original"},
+		},
+	}
+
+	tests := map[string]struct {
+		metadata            Metadata
+		expectedLabels      map[string]string
+		expectedAnnotations map[string]string
+	}{
+		"no metadata": {
+			metadata: Metadata{},
+			expectedLabels: map[string]string{
+				"env":                          "dev",
+				"objectset.rio.cattle.io/hash": "3bc15c8aae3e4124dd409035f32ea2fd6835efc9",
+			},
+			expectedAnnotations: map[string]string{
+				"note":                       "original",
+				"objectset.rio.cattle.io/id": "-",
+			},
+		},
+		"existing keys are kept": {
+			metadata: Metadata{
+				Labels:      map[string]string{"env": "staging", "owner": "me"},
+				Annotations: map[string]string{"note": "changed", "team": "a"},
+			},
+			expectedLabels: map[string]string{
+				"env":                          "dev",
+				"owner":                        "me",
+				"objectset.rio.cattle.io/hash": "3bc15c8aae3e4124dd409035f32ea2fd6835efc9",
+			},
+			expectedAnnotations: map[string]string{
+				"note":                       "original",
+				"team":                       "a",
+				"objectset.rio.cattle.io/id": "-",
+			},
+		},
+		"existing keys are overwritten": {
+			metadata: Metadata{
+				Labels:      map[string]string{"env": "staging", "objectset.rio.cattle.io/hash": "x"},
+				Annotations: map[string]string{"note": "changed"},
+				Overwrite:   true,
+			},
+			expectedLabels: map[string]string{
+				"env":                          "staging",
+				"objectset.rio.cattle.io/hash": "3bc15c8aae3e4124dd409035f32ea2fd6835efc9",
+			},
+			expectedAnnotations: map[string]string{
+				"note":                       "changed",
+				"objectset.rio.cattle.io/id": "-",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := yaml.ToBytes([]kruntime.Object{cm})
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+
+			pr := postRender{
+				manifest: &manifest.Manifest{
+					Resources: []v1alpha1.BundleResource{},
+				},
+				chart:    &chart.Chart{},
+				metadata: test.metadata,
+			}
+			postRenderedManifests, err := pr.Run(bytes.NewBuffer(data))
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+
+			objs, err := yaml.ToObjects(postRenderedManifests)
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+
+			m, err := meta.Accessor(objs[0])
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if !cmp.Equal(m.GetLabels(), test.expectedLabels) {
+				t.Errorf("expected labels %s, got %s", test.expectedLabels, m.GetLabels())
+			}
+			if !cmp.Equal(m.GetAnnotations(), test.expectedAnnotations) {
+				t.Errorf("expected annotations %s, got %s", test.expectedAnnotations, m.GetAnnotations())
+			}
+		})
+	}
+}
@@ -19,11 +19,13 @@ import (
 )
 
 // Template runs helm template and returns the resources as a list of objects, without applying them.
-func Template(ctx context.Context, bundleID string, manifest *manifest.Manifest, options fleet.BundleDeploymentOptions) ([]runtime.Object, error) {
+// The labels and annotations in metadata are added to every object.
+func Template(ctx context.Context, bundleID string, manifest *manifest.Manifest, options fleet.BundleDeploymentOptions, metadata Metadata) ([]runtime.Object, error) {
 	h := &Helm{
 		globalCfg:    action.Configuration{},
 		useGlobalCfg: true,
 		template:     true,
+		metadata:     metadata,
 	}
 
 	mem := driver.NewMemory()
@@ -0,0 +1,71 @@
+package sharding_test
+
+import (
+	"testing"
+
+	"github.com/rancher/fleet/pkg/sharding"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestFilterByShardID(t *testing.T) {
+	tests := map[string]struct {
+		shardID  string
+		labels   map[string]string
+		expected bool
+	}{
+		"unsharded controller, unlabeled resource": {
+			shardID:  "",
+			labels:   nil,
+			expected: true,
+		},
+		"unsharded controller, resource labeled for a shard": {
+			shardID:  "",
+			labels:   map[string]string{sharding.ShardingRefLabel: "shard1"},
+			expected: false,
+		},
+		"unsharded controller, resource with empty shard label": {
+			shardID:  "",
+			labels:   map[string]string{sharding.ShardingRefLabel: ""},
+			expected: false,
+		},
+		"sharded controller, unlabeled resource": {
+			shardID:  "shard1",
+			labels:   map[string]string{"other": "label"},
+			expected: false,
+		},
+		"sharded controller, resource labeled for the same shard": {
+			shardID:  "shard1",
+			labels:   map[string]string{sharding.ShardingRefLabel: "shard1"},
+			expected: true,
+		},
+		"sharded controller, resource labeled for another shard": {
+			shardID:  "shard1",
+			labels:   map[string]string{sharding.ShardingRefLabel: "shard2"},
+			expected: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: test.labels}}
+			old := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			p := sharding.FilterByShardID(test.shardID)
+
+			if got := p.Create(event.CreateEvent{Object: obj}); got != test.expected {
+				t.Errorf("create: expected %v, got %v", test.expected, got)
+			}
+			if got := p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: obj}); got != test.expected {
+				t.Errorf("update: expected %v, got %v", test.expected, got)
+			}
+			if got := p.Delete(event.DeleteEvent{Object: obj}); got != test.expected {
+				t.Errorf("delete: expected %v, got %v", test.expected, got)
+			}
+			if !p.Generic(event.GenericEvent{Object: obj}) {
+				t.Errorf("generic: expected generic events to pass the filter")
+			}
+		})
+	}
+}